@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Eventual-Inc/Daft/pkg/cache"
+	"github.com/Eventual-Inc/Daft/pkg/imagerunner"
+	"github.com/Eventual-Inc/Daft/pkg/jobstore"
+)
+
+// CreateJobRequest is the body of POST /v1/jobs.
+type CreateJobRequest struct {
+	Image          string             `json:"image"`
+	DatasetURI     string             `json:"dataset_uri,omitempty"`
+	Args           []string           `json:"args,omitempty"`
+	Env            map[string]string  `json:"env,omitempty"`
+	Resources      jobstore.Resources `json:"resources,omitempty"`
+	IdempotencyKey string             `json:"idempotency_key,omitempty"`
+}
+
+// jobServer wires the /v1/jobs HTTP surface to a jobstore.Store and the
+// imagerunner.Runner that actually executes containers.
+type jobServer struct {
+	store        *jobstore.Store
+	runner       *imagerunner.Runner
+	datasetCache *cache.Cache
+}
+
+func (s *jobServer) handleCreate(w http.ResponseWriter, req *http.Request) {
+	var body CreateJobRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if body.Image == "" {
+		http.Error(w, "image is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	job := jobstore.Job{
+		ID:             uuid.NewString(),
+		Image:          body.Image,
+		DatasetURI:     body.DatasetURI,
+		Args:           body.Args,
+		Env:            body.Env,
+		Resources:      body.Resources,
+		IdempotencyKey: body.IdempotencyKey,
+		Status:         jobstore.StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	// store.Create resolves a colliding IdempotencyKey atomically: on a
+	// collision it hands back the existing job with created=false instead
+	// of erroring, so a retried request gets the original job's 202 rather
+	// than a spurious conflict.
+	job, created, err := s.store.Create(job)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if created {
+		go s.run(job)
+	}
+
+	writeJobAccepted(w, job)
+}
+
+func writeJobAccepted(w http.ResponseWriter, job jobstore.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// run drives a job from pending through to a terminal status, persisting
+// progress as it goes so a daftlet restart can at least report where the
+// job got to.
+//
+// Every mutate passed to updateUnlessTerminal below leaves a job that
+// handleCancel already moved to StatusCancelled alone: cancellation can land
+// at any point — while the dataset is still downloading, before Submit, or
+// while the container is running — and none of those steps has its own
+// cancellation check, so run must not clobber that status on its way to
+// what it thinks is the next step.
+func (s *jobServer) run(job jobstore.Job) {
+	ctx := context.Background()
+
+	localMounts := map[string]string{}
+	if job.DatasetURI != "" {
+		dir, release, err := downloadAndExtractArchive(ctx, s.datasetCache, job.DatasetURI)
+		if err != nil {
+			s.updateUnlessTerminal(job.ID, func(j *jobstore.Job) {
+				j.Status = jobstore.StatusFailed
+				j.Error = err.Error()
+			})
+			return
+		}
+		// Held until run returns (i.e. past handle.Wait below), so the
+		// dataset directory stays pinned against eviction for as long as
+		// it's bind-mounted into the container.
+		defer release()
+		localMounts[dir] = "/data/dataset"
+	}
+
+	handle, err := s.runner.Submit(ctx, imagerunner.RunSpec{
+		Image:       job.Image,
+		Args:        job.Args,
+		Env:         job.Env,
+		LocalMounts: localMounts,
+	})
+	if err != nil {
+		s.updateUnlessTerminal(job.ID, func(j *jobstore.Job) {
+			j.Status = jobstore.StatusFailed
+			j.Error = err.Error()
+		})
+		return
+	}
+
+	if !s.updateUnlessTerminal(job.ID, func(j *jobstore.Job) {
+		j.Status = jobstore.StatusRunning
+		j.RunID = handle.ID
+	}) {
+		// The job was cancelled before RunID was persisted, so handleCancel
+		// never saw one to call Cancel on; do it here instead.
+		handle.Cancel()
+		return
+	}
+
+	exitCode, _, _, err := handle.Wait(ctx)
+	s.updateUnlessTerminal(job.ID, func(j *jobstore.Job) {
+		j.ExitCode = exitCode
+		switch {
+		case err != nil:
+			j.Status = jobstore.StatusFailed
+			j.Error = err.Error()
+		default:
+			j.Status = jobstore.StatusSucceeded
+		}
+	})
+}
+
+// updateUnlessTerminal applies mutate to the job unless it has already
+// reached a terminal status (most importantly StatusCancelled, set by
+// handleCancel racing with run), in which case it leaves the record alone
+// and reports false. It mirrors handleCancel's own read-check-write inside
+// a single store.Update transaction so the two can't race each other.
+func (s *jobServer) updateUnlessTerminal(id string, mutate func(*jobstore.Job)) bool {
+	applied := true
+	if err := s.store.Update(id, func(j *jobstore.Job) {
+		if isTerminalStatus(j.Status) {
+			applied = false
+			return
+		}
+		mutate(j)
+	}); err != nil {
+		return false
+	}
+	return applied
+}
+
+func isTerminalStatus(status jobstore.Status) bool {
+	switch status {
+	case jobstore.StatusSucceeded, jobstore.StatusFailed, jobstore.StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *jobServer) handleGet(w http.ResponseWriter, req *http.Request) {
+	job, err := s.store.Get(mux.Vars(req)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (s *jobServer) handleCancel(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+
+	// The pending/running check and the status write happen inside the same
+	// store.Update transaction, so a job that's already reached a terminal
+	// status (succeeded/failed/cancelled) never gets silently overwritten
+	// back to cancelled.
+	var alreadyTerminal bool
+	if err := s.store.Update(id, func(j *jobstore.Job) {
+		if isTerminalStatus(j.Status) {
+			alreadyTerminal = true
+			return
+		}
+		j.Status = jobstore.StatusCancelled
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if alreadyTerminal {
+		http.Error(w, "job is already in a terminal state", http.StatusConflict)
+		return
+	}
+
+	job, err := s.store.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if job.RunID != "" {
+		if handle, err := s.runner.Handle(job.RunID); err == nil {
+			handle.Cancel()
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLogs serves GET /v1/jobs/{id}/logs. With follow=true it streams
+// newly captured stdout as server-sent events until the run completes;
+// otherwise it returns everything captured so far in one response.
+func (s *jobServer) handleLogs(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	job, err := s.store.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if job.RunID == "" {
+		http.Error(w, "job has not started yet", http.StatusConflict)
+		return
+	}
+
+	handle, err := s.runner.Handle(job.RunID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if req.URL.Query().Get("follow") != "true" {
+		out, _ := handle.Stdout()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(out)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	done, _ := handle.Done()
+	var sent int
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		out, _ := handle.Stdout()
+		if len(out) > sent {
+			fmt.Fprintf(w, "data: %s\n\n", out[sent:])
+			sent = len(out)
+			flusher.Flush()
+		}
+
+		select {
+		case <-done:
+			return
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
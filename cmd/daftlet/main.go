@@ -1,218 +1,168 @@
 package main
 
 import (
-	"archive/zip"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-	"github.com/containerd/containerd/namespaces"
+	"github.com/Eventual-Inc/Daft/pkg/archive"
 
-	"github.com/gorilla/mux"
+	"github.com/Eventual-Inc/Daft/pkg/cache"
+
+	"github.com/Eventual-Inc/Daft/pkg/imagerunner"
 
-	"github.com/Eventual-Inc/Daft/pkg/container_runtime"
+	"github.com/Eventual-Inc/Daft/pkg/jobstore"
+
+	"github.com/Eventual-Inc/Daft/pkg/metrics"
 
 	"github.com/Eventual-Inc/Daft/pkg/objectstorage"
 )
 
 const ContainerHostPath = "/run/eventual/container/"
-const TestImagesZipS3Path = "s3://eventual-data-test-bucket/test-rickroll/rickroll-images.zip"
 const ContainerdSocket = "/run/containerd/containerd.sock"
+const DatasetCacheDir = "/var/cache/daftlet/datasets"
+const DatasetCacheMaxBytes = 64 << 30 // 64 GiB
+const JobStorePath = "/var/lib/daftlet/jobs.db"
 
-// Code that will launch a reader container using the host's containerd client
-func launchReader(id int, localImagesPath string) {
-	// Create a containerd client
-	ctx := namespaces.WithNamespace(context.Background(), "reader")
-
-	ImageURL := "941892620273.dkr.ecr.us-west-2.amazonaws.com/daft/reader:0"
-
-	thing := container_runtime.NewContainerRuntimeContext(ContainerdSocket, ContainerHostPath)
-	thing.PullImage(ctx, ImageURL)
-	containerName, _ := thing.CreateContainer(ctx, ImageURL)
-	thing.StartContainer(ctx, containerName)
-	thing.StopContainer(ctx, containerName)
-	thing.DeleteContainer(ctx, containerName)
-	thing.EvictImage(ctx, ImageURL)
-	return
-
-	// Sleep for a while to let container start UDS server
-	// time.Sleep(1 * time.Second)
-	// start = time.Now()
-
-	// // Send some data to the running task
-	// c, err := net.Dial("unix", sockAddr)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// defer c.Close()
-	// log.Printf("Time to dial UDS: %v", time.Since(start))
-
-	// files, err := ioutil.ReadDir(localImagesPath)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// for i, f := range files {
-	// 	data, err := os.ReadFile(filepath.Join(localImagesPath, f.Name()))
-	// 	if err != nil {
-	// 		log.Fatal(err)
-	// 	}
-
-	// 	log.Printf("Hash of file: %x", md5.Sum(data))
-
-	// 	start = time.Now()
-	// 	builder := flatbuffers.NewBuilder(len(data)) // figure out better initial sizing
-	// 	filedata := builder.CreateByteVector(data)
-	// 	fbs.FileStart(builder)
-	// 	fbs.FileAddData(builder, filedata)
-	// 	fileRecord := fbs.FileEnd(builder)
-	// 	builder.FinishSizePrefixed(fileRecord)
-	// 	log.Printf("%d Time to build Flatbuffer record: %v", i, time.Since(start))
-	// 	start = time.Now()
-
-	// 	_, err = c.Write(builder.FinishedBytes())
-	// 	log.Printf("%d Time to write Flatbuffer record to UDS: %v", i, time.Since(start))
-	// }
-
-	// start = time.Now()
-	// if err := task.Kill(ctx, syscall.SIGTERM); err != nil {
-	// 	log.Fatal(err)
-	// }
-	// log.Printf("Time to kill task: %v", time.Since(start))
-	// start = time.Now()
-
-	// // wait for the process to fully exit and print out the exit status
-	// start = time.Now()
-	// status := <-exitStatusC
-	// code, _, err := status.Result()
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// fmt.Printf("reader exited with status: %d\n", code)
-	// log.Printf("Time to finish task execution: %v", time.Since(start))
+type CacheStatsResponse struct {
+	Datasets cache.Stats                 `json:"datasets"`
+	Images   imagerunner.ImageCacheStats `json:"images"`
 }
 
-type IDDocument struct {
-	ID uint64 `json:"id"`
+type RunStatusResponse struct {
+	RunID  string                `json:"run_id"`
+	Status imagerunner.RunStatus `json:"status"`
 }
 
-func unzipSource(source, destination string) error {
-	// 1. Open the zip file
-	reader, err := zip.OpenReader(source)
+// downloadAndExtractArchive extracts the archive at uri (any scheme
+// supported by pkg/objectstorage.New) into datasetCache, keyed by the
+// object's ETag so a second request for the same object version is served
+// from disk instead of re-downloading and re-extracting. It returns the
+// directory the dataset was extracted into and a release func the caller
+// must call once it's done using that directory (e.g. once the container
+// bind-mounting it has exited), so the entry stays pinned against eviction
+// for as long as it's actually in use.
+func downloadAndExtractArchive(ctx context.Context, datasetCache *cache.Cache, uri string) (dir string, release func(), err error) {
+	objstore, path, err := objectstorage.New(ctx, uri, objectstorage.ConfigFromEnv())
 	if err != nil {
-		return err
+		return "", nil, err
 	}
-	defer reader.Close()
 
-	// 2. Get the absolute destination path
-	destination, err = filepath.Abs(destination)
+	info, err := objstore.StatObject(ctx, path)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
-	// 3. Iterate over zip files inside the archive and unzip each of them
-	for _, f := range reader.File {
-		err := unzipFile(f, destination)
+	key := cache.Key(info.ETag)
+	return datasetCache.Put(key, func(destDir string) error {
+		scratch, err := os.CreateTemp("", "daft-archive-*")
 		if err != nil {
 			return err
 		}
-	}
+		defer os.Remove(scratch.Name())
+		defer scratch.Close()
 
-	return nil
-}
-
-func unzipFile(f *zip.File, destination string) error {
-	// 4. Check if file paths are not vulnerable to Zip Slip
-	filePath := filepath.Join(destination, f.Name)
-	if !strings.HasPrefix(filePath, filepath.Clean(destination)+string(os.PathSeparator)) {
-		return fmt.Errorf("invalid file path: %s", filePath)
-	}
-
-	// 5. Create directory tree
-	if f.FileInfo().IsDir() {
-		if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
+		if _, err := objstore.DownloadObject(ctx, path, scratch); err != nil {
+			return err
+		}
+		if _, err := scratch.Seek(0, io.SeekStart); err != nil {
 			return err
 		}
-		return nil
-	}
-
-	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-		return err
-	}
 
-	// 6. Create a destination file for unzipped content
-	destinationFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-	if err != nil {
-		return err
-	}
-	defer destinationFile.Close()
+		return archive.DownloadAndExtract(ctx, scratch, info.Size, destDir, archive.Options{
+			SourceName: uri,
+		})
+	})
+}
 
-	// 7. Unzip the content of a file and copy it to the destination file
-	zippedFile, err := f.Open()
-	if err != nil {
-		return err
-	}
-	defer zippedFile.Close()
+func handleRunStatus(runner *imagerunner.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		runID := mux.Vars(req)["id"]
+		handle, err := runner.Handle(runID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		status, err := handle.Status()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 
-	if _, err := io.Copy(destinationFile, zippedFile); err != nil {
-		return err
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RunStatusResponse{RunID: runID, Status: status})
 	}
-	return nil
 }
 
-func DownloadS3File(s3Path string) (string, error) {
-	ctx := context.Background()
-	file, err := os.Create("/tmp/images.zip")
-	if err != nil {
-		return "", err
-	}
+func handleRunArtifacts(runner *imagerunner.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		runID := mux.Vars(req)["id"]
+		handle, err := runner.Handle(runID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 
-	defer file.Close()
+		dir, err := handle.Artifacts()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion("us-west-2"))
-	if err != nil {
-		return "", err
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+runID+`.zip"`)
+		if err := imagerunner.ArtifactsWriter(dir, w); err != nil {
+			log.Printf("failed to stream artifacts for run %s: %v", runID, err)
+		}
 	}
+}
 
-	objstore := objectstorage.NewAwsS3ObjectStore(ctx, cfg)
-	_, err = objstore.DownloadObject(ctx, s3Path, file)
-
-	if err != nil {
-		return "", err
+func handleCacheStats(datasetCache *cache.Cache, runner *imagerunner.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CacheStatsResponse{
+			Datasets: datasetCache.Stats(),
+			Images:   runner.ImageCacheStats(),
+		})
 	}
-
-	return file.Name(), nil
 }
 
 func main() {
-	// Download and unzip test images
-	localImagesZipPath, err := DownloadS3File(TestImagesZipS3Path)
-	log.Print("done downloading images from s3")
-
+	datasetCache, err := cache.New(DatasetCacheDir, DatasetCacheMaxBytes)
 	if err != nil {
 		log.Fatal(err)
 	}
-	localImagesDirPath := "/tmp/images"
-	err = unzipSource(localImagesZipPath, localImagesDirPath)
+
+	store, err := jobstore.Open(JobStorePath)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer store.Close()
+
+	runner := imagerunner.New(imagerunner.Config{
+		ContainerdSocket:  ContainerdSocket,
+		ContainerHostPath: ContainerHostPath,
+		Metrics:           metrics.New(prometheus.DefaultRegisterer),
+	})
 
-	launchReader(0, localImagesDirPath)
+	jobs := &jobServer{store: store, runner: runner, datasetCache: datasetCache}
 
 	r := mux.NewRouter()
-	r.HandleFunc("/launch-reader", func(w http.ResponseWriter, req *http.Request) {
-		decodedReq := new(IDDocument)
-		json.NewDecoder(req.Body).Decode(&decodedReq)
-		launchReader(int(decodedReq.ID), localImagesDirPath)
-	}).Methods("POST")
+	r.HandleFunc("/v1/jobs", jobs.handleCreate).Methods("POST")
+	r.HandleFunc("/v1/jobs/{id}", jobs.handleGet).Methods("GET")
+	r.HandleFunc("/v1/jobs/{id}", jobs.handleCancel).Methods("DELETE")
+	r.HandleFunc("/v1/jobs/{id}/logs", jobs.handleLogs).Methods("GET")
+	r.HandleFunc("/runs/{id}", handleRunStatus(runner)).Methods("GET")
+	r.HandleFunc("/runs/{id}/artifacts", handleRunArtifacts(runner)).Methods("GET")
+	r.HandleFunc("/cache/stats", handleCacheStats(datasetCache, runner)).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 
 	srv := &http.Server{
 		Addr:    ":8080",
@@ -0,0 +1,343 @@
+// Package archive provides streaming extraction of zip, tar, tar.gz, and
+// tar.zst archives into a destination directory, with the size and path
+// safeguards needed when the archive's contents are not trusted (e.g. a
+// dataset fetched from object storage on behalf of a reader container).
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format identifies the archive encoding to use when extracting.
+type Format int
+
+const (
+	// FormatUnknown means no format is fixed up front; DownloadAndExtract
+	// then sniffs it via DetectFormat, which also returns FormatUnknown when
+	// neither extension nor content-type matches.
+	FormatUnknown Format = iota
+	FormatZip
+	FormatTar
+	FormatTarGz
+	FormatTarZst
+)
+
+// DefaultMaxFileSize is the largest a single extracted file may be.
+const DefaultMaxFileSize = 4 << 30 // 4 GiB
+
+// DefaultMaxTotalSize is the largest the sum of all extracted files may be.
+const DefaultMaxTotalSize = 16 << 30 // 16 GiB
+
+// Options controls the limits and format detection applied during extraction.
+type Options struct {
+	// Format forces a specific archive format. If FormatUnknown, DetectFormat
+	// is used against SourceName and ContentType.
+	Format Format
+	// SourceName is the object key or file name, used for extension sniffing
+	// when Format is FormatUnknown.
+	SourceName string
+	// ContentType is the HTTP/S3 content-type of the source object, used for
+	// sniffing when Format is FormatUnknown and SourceName is ambiguous.
+	ContentType string
+	// MaxFileSize caps the decompressed size of any single entry. Zero means
+	// DefaultMaxFileSize.
+	MaxFileSize int64
+	// MaxTotalSize caps the sum of all decompressed entries. Zero means
+	// DefaultMaxTotalSize.
+	MaxTotalSize int64
+}
+
+func (o Options) maxFileSize() int64 {
+	if o.MaxFileSize > 0 {
+		return o.MaxFileSize
+	}
+	return DefaultMaxFileSize
+}
+
+func (o Options) maxTotalSize() int64 {
+	if o.MaxTotalSize > 0 {
+		return o.MaxTotalSize
+	}
+	return DefaultMaxTotalSize
+}
+
+// DetectFormat sniffs the archive format from a file name/key and, as a
+// fallback, a content-type string. It returns FormatUnknown if neither
+// yields a match.
+func DetectFormat(name, contentType string) Format {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return FormatZip
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return FormatTarZst
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return FormatTar
+	}
+
+	switch strings.ToLower(contentType) {
+	case "application/zip", "application/x-zip-compressed":
+		return FormatZip
+	case "application/zstd", "application/x-zstd":
+		return FormatTarZst
+	case "application/gzip", "application/x-gzip":
+		return FormatTarGz
+	case "application/x-tar":
+		return FormatTar
+	}
+
+	return FormatUnknown
+}
+
+// sizedReaderAt is implemented by sources that can report their total size,
+// letting DownloadAndExtract build a zip.Reader directly over the stream
+// instead of spilling to a temp file.
+type sizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// DownloadAndExtract streams src into destDir, applying Zip Slip protection,
+// symlink rejection, and the size limits in opts. src is read at most once
+// for tar formats; for zip it is read via ReaderAt when srcSize is known
+// (avoiding a temp file), and otherwise spilled to a temp file since
+// archive/zip requires random access.
+func DownloadAndExtract(ctx context.Context, src io.Reader, srcSize int64, destDir string, opts Options) error {
+	destDir, err := filepath.Abs(destDir)
+	if err != nil {
+		return fmt.Errorf("resolve destination: %w", err)
+	}
+
+	format := opts.Format
+	if format == FormatUnknown {
+		format = DetectFormat(opts.SourceName, opts.ContentType)
+	}
+
+	switch format {
+	case FormatZip:
+		return extractZip(ctx, src, srcSize, destDir, opts)
+	case FormatTar:
+		return extractTar(ctx, src, destDir, opts)
+	case FormatTarGz:
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return fmt.Errorf("open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		return extractTar(ctx, gz, destDir, opts)
+	case FormatTarZst:
+		zr, err := zstd.NewReader(src)
+		if err != nil {
+			return fmt.Errorf("open zstd stream: %w", err)
+		}
+		defer zr.Close()
+		return extractTar(ctx, zr.IOReadCloser(), destDir, opts)
+	default:
+		return fmt.Errorf("could not determine archive format for %q (content-type %q)", opts.SourceName, opts.ContentType)
+	}
+}
+
+func extractZip(ctx context.Context, src io.Reader, srcSize int64, destDir string, opts Options) error {
+	var ra io.ReaderAt
+	var size int64
+
+	if sra, ok := src.(sizedReaderAt); ok {
+		ra, size = sra, sra.Size()
+	} else if srcSize > 0 {
+		if at, ok := src.(io.ReaderAt); ok {
+			ra, size = at, srcSize
+		}
+	}
+
+	if ra == nil {
+		// archive/zip needs random access and the object's size wasn't
+		// known up front, so fall back to spilling to a temp file.
+		tmp, err := os.CreateTemp("", "daft-archive-*.zip")
+		if err != nil {
+			return fmt.Errorf("create temp file for zip: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		n, err := io.Copy(tmp, src)
+		if err != nil {
+			return fmt.Errorf("buffer zip to temp file: %w", err)
+		}
+		ra, size = tmp, n
+	}
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return fmt.Errorf("open zip archive: %w", err)
+	}
+
+	var totalSize int64
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := extractZipEntry(f, destDir, opts, &totalSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(f *zip.File, destDir string, opts Options, totalSize *int64) error {
+	filePath, err := safeJoin(destDir, f.Name)
+	if err != nil {
+		return err
+	}
+
+	mode := f.Mode()
+	if mode&os.ModeSymlink != 0 {
+		return fmt.Errorf("refusing to extract symlink %q", f.Name)
+	}
+
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(filePath, 0o755)
+	}
+
+	// f.UncompressedSize64 comes from the central directory, which an
+	// attacker fully controls; it is only a declared size, not a guarantee.
+	// We cap and tally actual decompressed bytes as they're written rather
+	// than trusting it, and reject the entry if what came out doesn't match
+	// what it claimed.
+	declared := int64(f.UncompressedSize64)
+	if declared > opts.maxFileSize() {
+		return fmt.Errorf("entry %q exceeds max file size (%d > %d)", f.Name, declared, opts.maxFileSize())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	bw := &boundedWriter{w: out, maxEntry: opts.maxFileSize(), total: totalSize, maxTotal: opts.maxTotalSize()}
+	if _, err := io.Copy(bw, in); err != nil {
+		return fmt.Errorf("extract %q: %w", f.Name, err)
+	}
+	if bw.n != declared {
+		return fmt.Errorf("entry %q declared size %d but extracted %d bytes", f.Name, declared, bw.n)
+	}
+	return nil
+}
+
+// boundedWriter enforces per-entry and running-total byte budgets as it
+// writes, so a decompression stream can't exceed the declared limits no
+// matter what the archive's header claims about entry sizes.
+type boundedWriter struct {
+	w        io.Writer
+	n        int64
+	maxEntry int64
+	total    *int64
+	maxTotal int64
+}
+
+func (b *boundedWriter) Write(p []byte) (int, error) {
+	if b.n+int64(len(p)) > b.maxEntry {
+		return 0, fmt.Errorf("exceeds max file size (%d)", b.maxEntry)
+	}
+	if *b.total+int64(len(p)) > b.maxTotal {
+		return 0, fmt.Errorf("archive exceeds max total size (%d)", b.maxTotal)
+	}
+
+	n, err := b.w.Write(p)
+	b.n += int64(n)
+	*b.total += int64(n)
+	return n, err
+}
+
+func extractTar(ctx context.Context, src io.Reader, destDir string, opts Options) error {
+	tr := tar.NewReader(src)
+	var totalSize int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar header: %w", err)
+		}
+
+		filePath, err := safeJoin(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(filePath, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("refusing to extract link %q", hdr.Name)
+		case tar.TypeReg:
+			// As with zip, hdr.Size is attacker-declared; cap and tally the
+			// actual bytes streamed out of the tar reader instead of trusting
+			// it, and reject on a declared/actual mismatch.
+			if hdr.Size > opts.maxFileSize() {
+				return fmt.Errorf("entry %q exceeds max file size (%d > %d)", hdr.Name, hdr.Size, opts.maxFileSize())
+			}
+
+			if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			bw := &boundedWriter{w: out, maxEntry: opts.maxFileSize(), total: &totalSize, maxTotal: opts.maxTotalSize()}
+			_, err = io.Copy(bw, tr)
+			out.Close()
+			if err != nil {
+				return fmt.Errorf("extract %q: %w", hdr.Name, err)
+			}
+			if bw.n != hdr.Size {
+				return fmt.Errorf("entry %q declared size %d but extracted %d bytes", hdr.Name, hdr.Size, bw.n)
+			}
+		default:
+			// Skip device files, fifos, and other entry types we don't need.
+			continue
+		}
+	}
+}
+
+// safeJoin joins name onto destDir and rejects the result if it escapes
+// destDir, guarding against Zip Slip style path traversal regardless of
+// archive format.
+func safeJoin(destDir, name string) (string, error) {
+	filePath := filepath.Join(destDir, name)
+	if !strings.HasPrefix(filePath, filepath.Clean(destDir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid file path: %s", name)
+	}
+	return filePath, nil
+}
@@ -0,0 +1,153 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func buildZip(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create entry %q: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("write entry %q: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadAndExtractZipSlip(t *testing.T) {
+	data := buildZip(t, map[string]string{"../escape.txt": "pwned"})
+	destDir := t.TempDir()
+
+	err := DownloadAndExtract(context.Background(), bytes.NewReader(data), int64(len(data)), destDir, Options{Format: FormatZip})
+	if err == nil {
+		t.Fatal("expected Zip Slip entry to be rejected, got nil error")
+	}
+
+	if _, statErr := os.Stat(filepath.Join(filepath.Dir(destDir), "escape.txt")); !os.IsNotExist(statErr) {
+		t.Fatal("escape.txt was written outside destDir")
+	}
+}
+
+func TestDownloadAndExtractRejectsSymlink(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	hdr := &zip.FileHeader{Name: "link"}
+	hdr.SetMode(os.ModeSymlink | 0o777)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		t.Fatalf("create symlink header: %v", err)
+	}
+	if _, err := w.Write([]byte("/etc/passwd")); err != nil {
+		t.Fatalf("write symlink target: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip writer: %v", err)
+	}
+	data := buf.Bytes()
+
+	destDir := t.TempDir()
+	err = DownloadAndExtract(context.Background(), bytes.NewReader(data), int64(len(data)), destDir, Options{Format: FormatZip})
+	if err == nil {
+		t.Fatal("expected symlink entry to be rejected, got nil error")
+	}
+}
+
+func TestDownloadAndExtractEnforcesMaxFileSize(t *testing.T) {
+	data := buildZip(t, map[string]string{"big.txt": "0123456789"})
+	destDir := t.TempDir()
+
+	err := DownloadAndExtract(context.Background(), bytes.NewReader(data), int64(len(data)), destDir, Options{
+		Format:      FormatZip,
+		MaxFileSize: 4,
+	})
+	if err == nil {
+		t.Fatal("expected entry exceeding MaxFileSize to be rejected, got nil error")
+	}
+}
+
+func TestDownloadAndExtractEnforcesMaxTotalSize(t *testing.T) {
+	data := buildZip(t, map[string]string{"a.txt": "aaaaa", "b.txt": "bbbbb"})
+	destDir := t.TempDir()
+
+	err := DownloadAndExtract(context.Background(), bytes.NewReader(data), int64(len(data)), destDir, Options{
+		Format:       FormatZip,
+		MaxTotalSize: 6,
+	})
+	if err == nil {
+		t.Fatal("expected archive exceeding MaxTotalSize to be rejected, got nil error")
+	}
+}
+
+func TestDownloadAndExtractZipHappyPath(t *testing.T) {
+	data := buildZip(t, map[string]string{"dir/file.txt": "hello"})
+	destDir := t.TempDir()
+
+	if err := DownloadAndExtract(context.Background(), bytes.NewReader(data), int64(len(data)), destDir, Options{Format: FormatZip}); err != nil {
+		t.Fatalf("DownloadAndExtract: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(destDir, "dir", "file.txt"))
+	if err != nil {
+		t.Fatalf("read extracted file: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("extracted content = %q, want %q", got, "hello")
+	}
+}
+
+func TestDownloadAndExtractTarRejectsPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "../escape.txt", Typeflag: tar.TypeReg, Size: 4, Mode: 0o644}); err != nil {
+		t.Fatalf("write tar header: %v", err)
+	}
+	if _, err := tw.Write([]byte("pwn!")); err != nil {
+		t.Fatalf("write tar body: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer: %v", err)
+	}
+	data := buf.Bytes()
+
+	destDir := t.TempDir()
+	err := DownloadAndExtract(context.Background(), bytes.NewReader(data), int64(len(data)), destDir, Options{Format: FormatTar})
+	if err == nil {
+		t.Fatal("expected tar path traversal to be rejected, got nil error")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		want        Format
+	}{
+		{name: "dataset.zip", want: FormatZip},
+		{name: "dataset.tar.gz", want: FormatTarGz},
+		{name: "dataset.tgz", want: FormatTarGz},
+		{name: "dataset.tar.zst", want: FormatTarZst},
+		{name: "dataset.tar", want: FormatTar},
+		{name: "dataset", contentType: "application/zip", want: FormatZip},
+		{name: "dataset", contentType: "", want: FormatUnknown},
+	}
+	for _, tc := range cases {
+		if got := DetectFormat(tc.name, tc.contentType); got != tc.want {
+			t.Errorf("DetectFormat(%q, %q) = %v, want %v", tc.name, tc.contentType, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package cache is a content-addressable, size-bounded on-disk cache for
+// artifacts daftlet would otherwise re-fetch on every run: today that's
+// extracted dataset directories, keyed by the source object's S3 ETag/
+// version. Entries are sharded by the first two characters of their key to
+// keep any one directory from growing huge, and least-recently-used entries
+// are evicted once the cache exceeds its size budget.
+//
+// Image layers are deliberately not cached here: containerd's own content
+// store already keeps pulled layer blobs on disk keyed by digest and shares
+// them across images, so layering a second digest-keyed cache on top of it
+// in this package would just duplicate that bookkeeping. pkg/imagerunner's
+// imageLRU tracks which image names are considered "warm" for pool sizing,
+// not layer storage.
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Key identifies a cache entry, e.g. an image digest ("sha256:...") or an S3
+// ETag. It is used verbatim as part of the on-disk path, so callers should
+// stick to filesystem-safe characters (digests and ETags already are).
+type Key string
+
+// Stats is returned by Cache.Stats for the /cache/stats observability
+// endpoint.
+type Stats struct {
+	Entries    int   `json:"entries"`
+	TotalBytes int64 `json:"total_bytes"`
+	MaxBytes   int64 `json:"max_bytes"`
+	Evictions  int64 `json:"evictions"`
+}
+
+type entry struct {
+	key     Key
+	path    string
+	size    int64
+	element *list.Element
+	pinned  int // number of outstanding Acquire calls not yet Released
+}
+
+// Cache is a size-bounded LRU of on-disk entries rooted at a single
+// directory. It is safe for concurrent use.
+type Cache struct {
+	rootDir  string
+	maxBytes int64
+
+	mu         sync.Mutex
+	entries    map[Key]*entry
+	order      *list.List // front = most recently used
+	totalBytes int64
+	evictions  int64
+}
+
+// New creates a Cache rooted at rootDir, which is created if it doesn't
+// exist. maxBytes bounds the sum of entry sizes; once exceeded, Put evicts
+// least-recently-used entries until the cache fits again. A maxBytes of zero
+// or less disables eviction.
+func New(rootDir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(rootDir, 0o755); err != nil {
+		return nil, fmt.Errorf("cache: create root dir: %w", err)
+	}
+	return &Cache{
+		rootDir:  rootDir,
+		maxBytes: maxBytes,
+		entries:  make(map[Key]*entry),
+		order:    list.New(),
+	}, nil
+}
+
+// shardedPath returns where key's entry lives on disk, sharding by its first
+// two characters so a single directory never holds every entry.
+func (c *Cache) shardedPath(key Key) string {
+	name := string(key)
+	shard := name
+	if len(name) > 2 {
+		shard = name[:2]
+	}
+	return filepath.Join(c.rootDir, shard, name)
+}
+
+// Get returns the on-disk path for key and marks it most-recently-used, or
+// ok=false if key isn't cached. Unlike Acquire, it does not pin the entry
+// against eviction; callers that will go on to use the path (rather than
+// just report on it, e.g. Stats) should call Acquire instead.
+func (c *Cache) Get(key Key) (path string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, found := c.entries[key]
+	if !found {
+		return "", false
+	}
+	c.order.MoveToFront(e.element)
+	return e.path, true
+}
+
+// Acquire returns the on-disk path for key, marks it most-recently-used,
+// and pins it so evictLocked won't remove it until the returned release
+// func is called. Callers that hand the path to something long-lived (e.g.
+// bind-mounting it into a running container) must hold the pin for as long
+// as that use lasts, or a concurrent Put for an unrelated key could evict
+// the directory out from under them. ok=false if key isn't cached, in which
+// case release is nil.
+func (c *Cache) Acquire(key Key) (path string, release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, found := c.entries[key]
+	if !found {
+		return "", nil, false
+	}
+	c.order.MoveToFront(e.element)
+	e.pinned++
+	return e.path, c.releaseFunc(key), true
+}
+
+// releaseFunc returns a func that unpins key's entry, for embedding in
+// Acquire/Put's return value. The returned func takes c.mu itself, so it
+// must not be called while already holding it.
+func (c *Cache) releaseFunc(key Key) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if e, found := c.entries[key]; found && e.pinned > 0 {
+				e.pinned--
+			}
+		})
+	}
+}
+
+// Put reserves a path for key and calls populate to fill it (write a file,
+// or extract a directory tree), then registers the entry and evicts LRU
+// entries until the cache is back under its byte budget. If key is already
+// cached, Put returns the existing path without calling populate.
+//
+// populate runs against a private scratch directory, not the final path, so
+// two concurrent Puts for the same key never populate the same directory at
+// once; the scratch directory is only moved into place once populate
+// succeeds and no other caller has already won the race. The entry's size
+// is measured from what populate actually wrote to disk rather than trusted
+// from the caller, since for something like an extracted archive that can
+// differ wildly from e.g. the compressed object's content-length.
+//
+// The returned entry is pinned, exactly as if acquired via Acquire: callers
+// must call the returned release func once they're done using path, and
+// until they do, evictLocked will skip this entry rather than deleting a
+// directory still in use (e.g. bind-mounted into a running container).
+func (c *Cache) Put(key Key, populate func(path string) error) (path string, release func(), err error) {
+	if path, release, ok := c.Acquire(key); ok {
+		return path, release, nil
+	}
+
+	path = c.shardedPath(key)
+	shardDir := filepath.Dir(path)
+	if err := os.MkdirAll(shardDir, 0o755); err != nil {
+		return "", nil, fmt.Errorf("cache: create shard dir: %w", err)
+	}
+
+	tmp, err := os.MkdirTemp(shardDir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("cache: create scratch dir: %w", err)
+	}
+	if err := populate(tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, err
+	}
+
+	size, err := dirSize(tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, fmt.Errorf("cache: measure populated entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, found := c.entries[key]; found {
+		// Lost a race with a concurrent Put for the same key; the winner's
+		// entry is already installed at path, so discard only our scratch
+		// copy, never path itself.
+		os.RemoveAll(tmp)
+		c.order.MoveToFront(e.element)
+		e.pinned++
+		return e.path, c.releaseFunc(key), nil
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, fmt.Errorf("cache: install entry: %w", err)
+	}
+
+	e := &entry{key: key, path: path, size: size, pinned: 1}
+	e.element = c.order.PushFront(e)
+	c.entries[key] = e
+	c.totalBytes += size
+
+	c.evictLocked()
+	return path, c.releaseFunc(key), nil
+}
+
+// dirSize returns the total size in bytes of all regular files under root,
+// walking recursively so it sizes both a single populated file and an
+// extracted directory tree the same way.
+func dirSize(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// evictLocked removes least-recently-used entries until the cache fits
+// within maxBytes, skipping any entry that's currently pinned by an
+// outstanding Acquire/Put (see releaseFunc). A long-running job holding a
+// pin can therefore leave the cache over budget until it releases; that's
+// preferable to deleting a directory still mounted into a running
+// container. Callers must hold c.mu.
+func (c *Cache) evictLocked() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for el := c.order.Back(); el != nil && c.totalBytes > c.maxBytes; {
+		e := el.Value.(*entry)
+		prev := el.Prev()
+		if e.pinned > 0 {
+			el = prev
+			continue
+		}
+		c.order.Remove(el)
+		delete(c.entries, e.key)
+		c.totalBytes -= e.size
+		c.evictions++
+		os.RemoveAll(e.path)
+		el = prev
+	}
+}
+
+// Stats reports the cache's current size and eviction count.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Entries:    len(c.entries),
+		TotalBytes: c.totalBytes,
+		MaxBytes:   c.maxBytes,
+		Evictions:  c.evictions,
+	}
+}
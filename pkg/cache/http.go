@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// gzipAwareTransport advertises Accept-Encoding: gzip on every request and
+// transparently decompresses responses that come back Content-Encoding:
+// gzip, so callers populating the cache from a plain HTTP object store (a
+// presigned URL, a CDN-fronted dataset mirror, …) don't have to think about
+// compression. Go's http.Transport does this on its own only when the
+// request carries no Accept-Encoding header of the caller's; this wrapper
+// sets the header itself and does the decompression explicitly. It suits
+// whole-object GETs only: a ranged fetch (such as s3manager's multipart
+// downloader issues) would have its parts decompressed out from under it.
+type gzipAwareTransport struct {
+	base http.RoundTripper
+}
+
+func (t *gzipAwareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("cache: open gzip response body: %w", err)
+		}
+		resp.Body = &gzipReadCloser{Reader: gz, underlying: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+	}
+
+	return resp, nil
+}
+
+// gzipReadCloser closes both the gzip reader and the underlying HTTP body.
+type gzipReadCloser struct {
+	*gzip.Reader
+	underlying io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	gzErr := g.Reader.Close()
+	if err := g.underlying.Close(); err != nil {
+		return err
+	}
+	return gzErr
+}
+
+// NewGzipAwareHTTPClient returns an http.Client that transparently decodes
+// gzip-encoded responses, for fetching cache entries from a plain HTTP
+// object store URL rather than through a cloud SDK.
+func NewGzipAwareHTTPClient(base *http.Client) *http.Client {
+	if base == nil {
+		base = &http.Client{}
+	}
+	transport := base.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	clone := *base
+	clone.Transport = &gzipAwareTransport{base: transport}
+	return &clone
+}
@@ -0,0 +1,254 @@
+// Package container_runtime wraps a containerd client to drive the
+// lifecycle of reader containers (pull, create, start, stop, delete, evict)
+// and to run a workload to completion inside an already-started container,
+// on behalf of pkg/imagerunner.
+package container_runtime
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"syscall"
+
+	"context"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/oci"
+	"github.com/google/uuid"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// ContainerRuntimeContext drives container lifecycle operations against a
+// single containerd daemon over containerdSocket, staging per-container
+// artifacts under containerHostPath.
+type ContainerRuntimeContext struct {
+	containerdSocket  string
+	containerHostPath string
+
+	client *containerd.Client
+}
+
+// NewContainerRuntimeContext returns a ContainerRuntimeContext for the given
+// containerd socket. The connection is established lazily on first use, so
+// constructing one never fails outright.
+func NewContainerRuntimeContext(containerdSocket, containerHostPath string) *ContainerRuntimeContext {
+	return &ContainerRuntimeContext{
+		containerdSocket:  containerdSocket,
+		containerHostPath: containerHostPath,
+	}
+}
+
+func (c *ContainerRuntimeContext) dial() (*containerd.Client, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	client, err := containerd.New(c.containerdSocket)
+	if err != nil {
+		return nil, fmt.Errorf("container_runtime: dial containerd at %s: %w", c.containerdSocket, err)
+	}
+	c.client = client
+	return c.client, nil
+}
+
+// PullImage pulls imageURL into the local content store. Failures are
+// logged rather than returned since it's invoked as a fire-and-forget
+// warm-up by pkg/imagerunner's image LRU.
+func (c *ContainerRuntimeContext) PullImage(ctx context.Context, imageURL string) {
+	client, err := c.dial()
+	if err != nil {
+		log.Printf("container_runtime: pull %s: %v", imageURL, err)
+		return
+	}
+	if _, err := client.Pull(ctx, imageURL, containerd.WithPullUnpack); err != nil {
+		log.Printf("container_runtime: pull %s: %v", imageURL, err)
+	}
+}
+
+// CreateContainer creates (but does not start) a container from imageURL
+// with no host bind mounts, suitable for the warm container pool where the
+// same container is reused across runs of the same image.
+func (c *ContainerRuntimeContext) CreateContainer(ctx context.Context, imageURL string) (string, error) {
+	return c.createContainer(ctx, imageURL, nil)
+}
+
+// CreateContainerWithMounts creates (but does not start) a container from
+// imageURL with localMounts (host path -> container path) baked into its
+// spec. A container created this way must never be returned to the warm
+// pool: its mounts are fixed at creation time, so reusing it for a
+// different run's mounts would silently run against the wrong data.
+func (c *ContainerRuntimeContext) CreateContainerWithMounts(ctx context.Context, imageURL string, localMounts map[string]string) (string, error) {
+	return c.createContainer(ctx, imageURL, localMounts)
+}
+
+func (c *ContainerRuntimeContext) createContainer(ctx context.Context, imageURL string, localMounts map[string]string) (string, error) {
+	client, err := c.dial()
+	if err != nil {
+		return "", err
+	}
+
+	image, err := client.GetImage(ctx, imageURL)
+	if err != nil {
+		image, err = client.Pull(ctx, imageURL, containerd.WithPullUnpack)
+		if err != nil {
+			return "", fmt.Errorf("container_runtime: pull %s: %w", imageURL, err)
+		}
+	}
+
+	name := "reader-" + uuid.NewString()
+	specOpts := []oci.SpecOpts{oci.WithImageConfig(image)}
+	for hostPath, containerPath := range localMounts {
+		specOpts = append(specOpts, oci.WithMounts([]specs.Mount{{
+			Type:        "bind",
+			Source:      hostPath,
+			Destination: containerPath,
+			Options:     []string{"rbind", "rw"},
+		}}))
+	}
+
+	_, err = client.NewContainer(ctx, name,
+		containerd.WithNewSnapshot(name+"-snapshot", image),
+		containerd.WithNewSpec(specOpts...),
+	)
+	if err != nil {
+		return "", fmt.Errorf("container_runtime: create container %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// StartContainer creates and starts the container's init task.
+func (c *ContainerRuntimeContext) StartContainer(ctx context.Context, name string) error {
+	client, err := c.dial()
+	if err != nil {
+		return err
+	}
+	container, err := client.LoadContainer(ctx, name)
+	if err != nil {
+		return fmt.Errorf("container_runtime: load container %s: %w", name, err)
+	}
+	task, err := container.NewTask(ctx, cio.NewCreator(cio.WithStdio))
+	if err != nil {
+		return fmt.Errorf("container_runtime: create task for %s: %w", name, err)
+	}
+	if err := task.Start(ctx); err != nil {
+		return fmt.Errorf("container_runtime: start task for %s: %w", name, err)
+	}
+	return nil
+}
+
+// StopContainer sends SIGTERM to the container's task.
+func (c *ContainerRuntimeContext) StopContainer(ctx context.Context, name string) error {
+	client, err := c.dial()
+	if err != nil {
+		return err
+	}
+	container, err := client.LoadContainer(ctx, name)
+	if err != nil {
+		return fmt.Errorf("container_runtime: load container %s: %w", name, err)
+	}
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("container_runtime: load task for %s: %w", name, err)
+	}
+	return task.Kill(ctx, syscall.SIGTERM)
+}
+
+// DeleteContainer tears down the container's task (if any) and removes the
+// container along with its snapshot.
+func (c *ContainerRuntimeContext) DeleteContainer(ctx context.Context, name string) error {
+	client, err := c.dial()
+	if err != nil {
+		return err
+	}
+	container, err := client.LoadContainer(ctx, name)
+	if err != nil {
+		return fmt.Errorf("container_runtime: load container %s: %w", name, err)
+	}
+	if task, err := container.Task(ctx, nil); err == nil {
+		task.Kill(ctx, syscall.SIGKILL)
+		task.Delete(ctx)
+	}
+	return container.Delete(ctx, containerd.WithSnapshotCleanup)
+}
+
+// EvictImage removes imageURL from the local content store so a later pull
+// starts fresh.
+func (c *ContainerRuntimeContext) EvictImage(ctx context.Context, imageURL string) error {
+	client, err := c.dial()
+	if err != nil {
+		return err
+	}
+	return client.ImageService().Delete(ctx, imageURL)
+}
+
+// RunOptions configures a single RunToCompletion invocation against an
+// already-started container.
+type RunOptions struct {
+	Args   []string
+	Env    map[string]string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// RunToCompletion execs opts.Args as a new process inside name's running
+// task, streams its stdout/stderr to opts.Stdout/opts.Stderr, and blocks
+// until it exits or ctx is cancelled. artifactsDir is the host-side
+// directory the container should have written output files to.
+//
+// Host bind mounts are not configurable here: they're fixed on the
+// container at creation time (see CreateContainerWithMounts), since a
+// running task's mount namespace can't be changed per-exec.
+func (c *ContainerRuntimeContext) RunToCompletion(ctx context.Context, name string, opts RunOptions) (exitCode int, artifactsDir string, err error) {
+	client, err := c.dial()
+	if err != nil {
+		return 0, "", err
+	}
+	container, err := client.LoadContainer(ctx, name)
+	if err != nil {
+		return 0, "", fmt.Errorf("container_runtime: load container %s: %w", name, err)
+	}
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("container_runtime: load task for %s: %w", name, err)
+	}
+
+	spec, err := container.Spec(ctx)
+	if err != nil {
+		return 0, "", fmt.Errorf("container_runtime: load spec for %s: %w", name, err)
+	}
+	process := spec.Process
+	if len(opts.Args) > 0 {
+		process.Args = opts.Args
+	}
+	for k, v := range opts.Env {
+		process.Env = append(process.Env, k+"="+v)
+	}
+
+	execID := "run-" + uuid.NewString()
+	exitProcess, err := task.Exec(ctx, execID, process, cio.NewCreator(cio.WithStreams(nil, opts.Stdout, opts.Stderr)))
+	if err != nil {
+		return 0, "", fmt.Errorf("container_runtime: exec in %s: %w", name, err)
+	}
+
+	statusC, err := exitProcess.Wait(ctx)
+	if err != nil {
+		return 0, "", fmt.Errorf("container_runtime: wait for exec in %s: %w", name, err)
+	}
+	if err := exitProcess.Start(ctx); err != nil {
+		return 0, "", fmt.Errorf("container_runtime: start exec in %s: %w", name, err)
+	}
+
+	select {
+	case status := <-statusC:
+		code, _, err := status.Result()
+		if err != nil {
+			return 0, "", fmt.Errorf("container_runtime: exec in %s: %w", name, err)
+		}
+		exitProcess.Delete(ctx)
+		return int(code), filepath.Join(c.containerHostPath, name, "artifacts"), nil
+	case <-ctx.Done():
+		exitProcess.Kill(ctx, syscall.SIGKILL)
+		return 0, "", ctx.Err()
+	}
+}
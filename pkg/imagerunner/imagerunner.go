@@ -0,0 +1,505 @@
+// Package imagerunner drives reader containers through containerd on behalf
+// of the daftlet HTTP handlers. It keeps a warm pool of containers per image
+// and an LRU of pulled images so a burst of requests doesn't pay pull and
+// container-create latency on every call.
+package imagerunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/containerd/containerd/namespaces"
+
+	"github.com/Eventual-Inc/Daft/pkg/container_runtime"
+)
+
+// RunSpec describes a single invocation of a reader container.
+type RunSpec struct {
+	Image       string
+	Args        []string
+	Env         map[string]string
+	LocalMounts map[string]string // host path -> container path
+	Timeout     time.Duration     // zero means Config.DefaultTimeout
+}
+
+// RunStatus is the lifecycle state of a run.
+type RunStatus string
+
+const (
+	RunPending   RunStatus = "pending"
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+)
+
+// RunHandle is returned by Submit and lets callers poll or wait on a run.
+type RunHandle struct {
+	ID string
+
+	runner *Runner
+}
+
+// Wait blocks until the run completes, the passed ctx is cancelled, or the
+// run's own timeout elapses, then returns its exit code and captured output.
+func (h RunHandle) Wait(ctx context.Context) (exitCode int, stdout, stderr []byte, err error) {
+	r, ok := h.runner.get(h.ID)
+	if !ok {
+		return 0, nil, nil, fmt.Errorf("imagerunner: unknown run %q", h.ID)
+	}
+
+	select {
+	case <-r.done:
+		return r.exitCode, r.stdout.Bytes(), r.stderr.Bytes(), r.err
+	case <-ctx.Done():
+		return 0, nil, nil, ctx.Err()
+	}
+}
+
+// Status returns the current lifecycle state of the run.
+func (h RunHandle) Status() (RunStatus, error) {
+	r, ok := h.runner.get(h.ID)
+	if !ok {
+		return "", fmt.Errorf("imagerunner: unknown run %q", h.ID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status, nil
+}
+
+// Stdout returns the bytes captured from the container's stdout so far;
+// unlike Wait, it may be called before the run completes, for tailing logs.
+func (h RunHandle) Stdout() ([]byte, error) {
+	r, ok := h.runner.get(h.ID)
+	if !ok {
+		return nil, fmt.Errorf("imagerunner: unknown run %q", h.ID)
+	}
+	return r.stdout.Bytes(), nil
+}
+
+// Stderr returns the bytes captured from the container's stderr so far; see
+// Stdout.
+func (h RunHandle) Stderr() ([]byte, error) {
+	r, ok := h.runner.get(h.ID)
+	if !ok {
+		return nil, fmt.Errorf("imagerunner: unknown run %q", h.ID)
+	}
+	return r.stderr.Bytes(), nil
+}
+
+// Done returns a channel that's closed once the run completes, for callers
+// that want to select on it alongside their own cancellation.
+func (h RunHandle) Done() (<-chan struct{}, error) {
+	r, ok := h.runner.get(h.ID)
+	if !ok {
+		return nil, fmt.Errorf("imagerunner: unknown run %q", h.ID)
+	}
+	return r.done, nil
+}
+
+// Cancel requests that the run stop; its status becomes RunCancelled once
+// the in-flight container operation observes the cancellation.
+func (h RunHandle) Cancel() error {
+	r, ok := h.runner.get(h.ID)
+	if !ok {
+		return fmt.Errorf("imagerunner: unknown run %q", h.ID)
+	}
+	r.mu.Lock()
+	cancel := r.cancel
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// Artifacts returns the directory the container wrote output files to, so
+// callers can zip it up for download. It is only valid once the run has
+// completed.
+func (h RunHandle) Artifacts() (string, error) {
+	r, ok := h.runner.get(h.ID)
+	if !ok {
+		return "", fmt.Errorf("imagerunner: unknown run %q", h.ID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status != RunSucceeded && r.status != RunFailed {
+		return "", fmt.Errorf("imagerunner: run %q has not completed", h.ID)
+	}
+	return r.artifactsDir, nil
+}
+
+type run struct {
+	spec   RunSpec
+	cancel context.CancelFunc
+
+	mu           sync.Mutex
+	status       RunStatus
+	exitCode     int
+	stdout       stdBuf
+	stderr       stdBuf
+	artifactsDir string
+	err          error
+	finishedAt   time.Time
+
+	done chan struct{}
+}
+
+// stdBuf is a tiny concurrency-safe byte buffer; captured stdout/stderr is
+// small enough (reader logs, not dataset payloads) that buffering it in
+// memory is fine.
+type stdBuf struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *stdBuf) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+func (b *stdBuf) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]byte, len(b.buf))
+	copy(out, b.buf)
+	return out
+}
+
+// Config controls pool sizing and defaults for a Runner.
+type Config struct {
+	// ContainerdSocket is the path to the containerd UDS.
+	ContainerdSocket string
+	// ContainerHostPath is the host directory under which containers'
+	// artifacts directories, and the per-run copies of them, are kept.
+	ContainerHostPath string
+	// MaxConcurrentRuns bounds how many containers may be active at once.
+	// Zero means 4.
+	MaxConcurrentRuns int
+	// WarmContainersPerImage is how many idle containers to keep ready per
+	// image so Submit can skip create+start latency. Zero means 1.
+	WarmContainersPerImage int
+	// MaxCachedImages bounds the image LRU. Zero means 8.
+	MaxCachedImages int
+	// DefaultTimeout is applied to a RunSpec that doesn't set one. Zero
+	// means 5 minutes.
+	DefaultTimeout time.Duration
+	// RunTTL bounds how long a completed run's record (including its
+	// buffered stdout/stderr) is kept around for Handle/Wait/Stdout lookups
+	// before the reaper frees it. Zero means 15 minutes.
+	RunTTL time.Duration
+	// Metrics, if set, is notified of pulls, starts, run durations, and
+	// failures for the /metrics endpoint. Nil disables recording.
+	Metrics MetricsRecorder
+}
+
+// MetricsRecorder receives lifecycle events from a Runner. It's an
+// interface rather than a concrete *metrics.Metrics so this package doesn't
+// have to depend on the Prometheus client.
+type MetricsRecorder interface {
+	RecordPull(image string)
+	RecordStart(image string)
+	RecordRunDuration(image string, d time.Duration)
+	RecordFailure(class string)
+}
+
+type noopMetricsRecorder struct{}
+
+func (noopMetricsRecorder) RecordPull(string)                       {}
+func (noopMetricsRecorder) RecordStart(string)                      {}
+func (noopMetricsRecorder) RecordRunDuration(string, time.Duration) {}
+func (noopMetricsRecorder) RecordFailure(string)                    {}
+
+// Runner pulls, pools, and executes reader containers.
+type Runner struct {
+	cfg    Config
+	rt     *container_runtime.ContainerRuntimeContext
+	ctx    context.Context
+	sem    chan struct{}
+	images *imageLRU
+	pool   *containerPool
+	runsMu sync.RWMutex
+	runs   map[string]*run
+}
+
+// New constructs a Runner against the given containerd socket. The returned
+// Runner owns a single containerd namespace ("reader") for its lifetime.
+func New(cfg Config) *Runner {
+	if cfg.MaxConcurrentRuns <= 0 {
+		cfg.MaxConcurrentRuns = 4
+	}
+	if cfg.WarmContainersPerImage <= 0 {
+		cfg.WarmContainersPerImage = 1
+	}
+	if cfg.MaxCachedImages <= 0 {
+		cfg.MaxCachedImages = 8
+	}
+	if cfg.DefaultTimeout <= 0 {
+		cfg.DefaultTimeout = 5 * time.Minute
+	}
+	if cfg.RunTTL <= 0 {
+		cfg.RunTTL = 15 * time.Minute
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = noopMetricsRecorder{}
+	}
+
+	rt := container_runtime.NewContainerRuntimeContext(cfg.ContainerdSocket, cfg.ContainerHostPath)
+	ctx := namespaces.WithNamespace(context.Background(), "reader")
+
+	r := &Runner{
+		cfg:  cfg,
+		rt:   rt,
+		ctx:  ctx,
+		sem:  make(chan struct{}, cfg.MaxConcurrentRuns),
+		runs: make(map[string]*run),
+	}
+	r.images = newImageLRU(cfg.MaxCachedImages, r.evictImage)
+	r.pool = newContainerPool(cfg.WarmContainersPerImage)
+	go r.reapLoop()
+	return r
+}
+
+// reapLoop periodically frees the records of runs that finished more than
+// cfg.RunTTL ago, so a long-lived daftlet doesn't accumulate an unbounded
+// number of completed runs (and their buffered stdout/stderr) in memory.
+func (r *Runner) reapLoop() {
+	ticker := time.NewTicker(r.cfg.RunTTL / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.reapOnce(time.Now())
+		}
+	}
+}
+
+func (r *Runner) reapOnce(now time.Time) {
+	cutoff := now.Add(-r.cfg.RunTTL)
+
+	r.runsMu.Lock()
+	defer r.runsMu.Unlock()
+	for id, rn := range r.runs {
+		rn.mu.Lock()
+		expired := isTerminal(rn.status) && !rn.finishedAt.IsZero() && rn.finishedAt.Before(cutoff)
+		rn.mu.Unlock()
+		if expired {
+			delete(r.runs, id)
+		}
+	}
+}
+
+func isTerminal(status RunStatus) bool {
+	switch status {
+	case RunSucceeded, RunFailed, RunCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Runner) get(id string) (*run, bool) {
+	r.runsMu.RLock()
+	defer r.runsMu.RUnlock()
+	rn, ok := r.runs[id]
+	return rn, ok
+}
+
+// ImageCacheStats reports the warm-image LRU's current occupancy, for the
+// /cache/stats endpoint.
+func (r *Runner) ImageCacheStats() ImageCacheStats {
+	return r.images.Stats()
+}
+
+// Handle looks up a previously submitted run by ID, for handlers like
+// GET /runs/{id} that only have the ID from the URL path.
+func (r *Runner) Handle(id string) (RunHandle, error) {
+	if _, ok := r.get(id); !ok {
+		return RunHandle{}, fmt.Errorf("imagerunner: unknown run %q", id)
+	}
+	return RunHandle{ID: id, runner: r}, nil
+}
+
+// Submit schedules spec to run and returns immediately with a handle; the
+// container is pulled/started and executed on a background goroutine bounded
+// by Runner's concurrency semaphore.
+func (r *Runner) Submit(ctx context.Context, spec RunSpec) (RunHandle, error) {
+	id := uuid.NewString()
+	ctx, cancel := context.WithCancel(ctx)
+	rn := &run{spec: spec, status: RunPending, cancel: cancel, done: make(chan struct{})}
+
+	r.runsMu.Lock()
+	r.runs[id] = rn
+	r.runsMu.Unlock()
+
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = r.cfg.DefaultTimeout
+	}
+
+	go r.execute(ctx, id, rn, timeout)
+
+	return RunHandle{ID: id, runner: r}, nil
+}
+
+func (r *Runner) execute(parent context.Context, id string, rn *run, timeout time.Duration) {
+	defer func() {
+		rn.mu.Lock()
+		rn.finishedAt = time.Now()
+		rn.mu.Unlock()
+		rn.cancel()
+		close(rn.done)
+	}()
+
+	started := time.Now()
+
+	select {
+	case r.sem <- struct{}{}:
+	case <-parent.Done():
+		rn.mu.Lock()
+		rn.status = RunCancelled
+		rn.err = parent.Err()
+		rn.mu.Unlock()
+		return
+	}
+	defer func() { <-r.sem }()
+
+	ctx, cancel := context.WithTimeout(withReaderNamespace(parent), timeout)
+	defer cancel()
+
+	rn.mu.Lock()
+	rn.status = RunRunning
+	rn.mu.Unlock()
+
+	pull := func(ctx context.Context, image string) {
+		r.cfg.Metrics.RecordPull(image)
+		r.rt.PullImage(ctx, image)
+	}
+	if err := r.images.touch(ctx, rn.spec.Image, pull); err != nil {
+		r.fail(rn, "pull", err)
+		return
+	}
+
+	container, warm, err := r.acquireContainer(ctx, rn.spec)
+	if err != nil {
+		if ctx.Err() != nil {
+			rn.mu.Lock()
+			rn.status = RunCancelled
+			rn.err = ctx.Err()
+			rn.mu.Unlock()
+			r.cfg.Metrics.RecordFailure("cancelled")
+			return
+		}
+		r.fail(rn, "start", err)
+		return
+	}
+	r.cfg.Metrics.RecordStart(rn.spec.Image)
+	if warm {
+		defer r.pool.release(ctx, rn.spec.Image, container, r.rt)
+	} else {
+		defer r.rt.DeleteContainer(ctx, container)
+	}
+
+	exitCode, artifactsDir, err := r.rt.RunToCompletion(ctx, container, container_runtime.RunOptions{
+		Args:   rn.spec.Args,
+		Env:    rn.spec.Env,
+		Stdout: &rn.stdout,
+		Stderr: &rn.stderr,
+	})
+	r.cfg.Metrics.RecordRunDuration(rn.spec.Image, time.Since(started))
+
+	if warm {
+		artifactsDir = r.scopeArtifacts(id, artifactsDir)
+	}
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.exitCode = exitCode
+	rn.artifactsDir = artifactsDir
+	if ctx.Err() != nil {
+		rn.status = RunCancelled
+		rn.err = ctx.Err()
+		r.cfg.Metrics.RecordFailure("cancelled")
+		return
+	}
+	if err != nil {
+		rn.status = RunFailed
+		rn.err = err
+		r.cfg.Metrics.RecordFailure("run")
+		return
+	}
+	rn.status = RunSucceeded
+}
+
+// scopeArtifacts moves a pooled container's artifacts directory to a
+// location keyed by run id, so a later run reusing that same container (see
+// containerPool) doesn't overwrite this run's output before a client has
+// had a chance to call Artifacts. It returns the path callers should use
+// going forward, or the empty string if there was nothing to move.
+func (r *Runner) scopeArtifacts(id, artifactsDir string) string {
+	if artifactsDir == "" {
+		return ""
+	}
+	scoped := filepath.Join(r.cfg.ContainerHostPath, "runs", id, "artifacts")
+	if err := os.MkdirAll(filepath.Dir(scoped), 0o755); err != nil {
+		return artifactsDir
+	}
+	if err := os.Rename(artifactsDir, scoped); err != nil {
+		return artifactsDir
+	}
+	return scoped
+}
+
+// acquireContainer returns a container to run spec in, and reports whether
+// it came from the warm pool (and should therefore be released back to it
+// rather than deleted). A spec with LocalMounts always gets a freshly
+// created container: a pooled container's mounts are fixed when it's
+// created, so handing a warm container with yesterday's mounts (or none) to
+// a run expecting a dataset at LocalMounts would silently run against the
+// wrong data.
+func (r *Runner) acquireContainer(ctx context.Context, spec RunSpec) (container string, warm bool, err error) {
+	if len(spec.LocalMounts) > 0 {
+		name, err := r.rt.CreateContainerWithMounts(ctx, spec.Image, spec.LocalMounts)
+		if err != nil {
+			return "", false, err
+		}
+		if err := r.rt.StartContainer(ctx, name); err != nil {
+			return "", false, err
+		}
+		return name, false, nil
+	}
+	return r.pool.acquire(ctx, spec.Image, r.rt)
+}
+
+func (r *Runner) fail(rn *run, class string, err error) {
+	rn.mu.Lock()
+	rn.status = RunFailed
+	rn.err = err
+	rn.mu.Unlock()
+	r.cfg.Metrics.RecordFailure(class)
+}
+
+func (r *Runner) evictImage(ctx context.Context, image string) {
+	r.rt.EvictImage(ctx, image)
+}
+
+func withReaderNamespace(ctx context.Context) context.Context {
+	return namespaces.WithNamespace(ctx, "reader")
+}
+
+// ArtifactsWriter copies the files under dir into a zip stream, for handlers
+// that serve GET /runs/{id}/artifacts.
+func ArtifactsWriter(dir string, w io.Writer) error {
+	return writeZip(dir, w)
+}
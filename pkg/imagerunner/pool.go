@@ -0,0 +1,150 @@
+package imagerunner
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/Eventual-Inc/Daft/pkg/container_runtime"
+)
+
+// imageLRU tracks, by image name, which images this Runner currently
+// considers warm and evicts the least-recently-used one once more than max
+// are resident, instead of the evict-on-every-request behaviour the inline
+// handler used to have. It is purely an in-memory bookkeeping structure: the
+// actual layer blobs it's nudging containerd to keep or evict live in
+// containerd's own content store, keyed by digest, not in this package.
+type imageLRU struct {
+	max    int
+	evict  func(ctx context.Context, image string)
+	mu     sync.Mutex
+	order  *list.List
+	lookup map[string]*list.Element
+	pulled map[string]bool
+
+	evictions int64
+}
+
+// ImageCacheStats reports the image LRU's current occupancy for the
+// /cache/stats endpoint.
+type ImageCacheStats struct {
+	Images    int   `json:"images"`
+	MaxImages int   `json:"max_images"`
+	Evictions int64 `json:"evictions"`
+}
+
+func newImageLRU(max int, evict func(ctx context.Context, image string)) *imageLRU {
+	return &imageLRU{
+		max:    max,
+		evict:  evict,
+		order:  list.New(),
+		lookup: make(map[string]*list.Element),
+		pulled: make(map[string]bool),
+	}
+}
+
+// touch marks image as recently used, pulling it via pull if it isn't
+// already resident, and evicting the LRU entry if that pushes the cache over
+// its configured size.
+func (c *imageLRU) touch(ctx context.Context, image string, pull func(ctx context.Context, image string)) error {
+	c.mu.Lock()
+	if el, ok := c.lookup[image]; ok {
+		c.order.MoveToFront(el)
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	pull(ctx, image)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.lookup[image]; ok {
+		return nil
+	}
+	c.lookup[image] = c.order.PushFront(image)
+	c.pulled[image] = true
+
+	for c.order.Len() > c.max {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		lru := back.Value.(string)
+		c.order.Remove(back)
+		delete(c.lookup, lru)
+		delete(c.pulled, lru)
+		c.evictions++
+		c.evict(ctx, lru)
+	}
+	return nil
+}
+
+// Stats reports the LRU's current occupancy.
+func (c *imageLRU) Stats() ImageCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ImageCacheStats{
+		Images:    c.order.Len(),
+		MaxImages: c.max,
+		Evictions: c.evictions,
+	}
+}
+
+// containerPool keeps up to warmPerImage idle, already-created containers
+// per image so Submit can skip container_runtime.CreateContainer+
+// StartContainer on the common case. Only containers created without host
+// bind mounts are ever pooled here; see Runner.acquireContainer.
+type containerPool struct {
+	warmPerImage int
+	mu           sync.Mutex
+	idle         map[string][]string // image -> idle container names
+}
+
+func newContainerPool(warmPerImage int) *containerPool {
+	return &containerPool{
+		warmPerImage: warmPerImage,
+		idle:         make(map[string][]string),
+	}
+}
+
+// acquire returns a container for image, reusing a warm one if available and
+// otherwise creating and starting a fresh one. The bool return reports
+// whether the container is eligible to go back into the warm pool once the
+// caller is done with it — true in both cases, since a freshly created
+// container is just as reusable as one that was already idle; callers
+// should release it via release rather than deleting it.
+func (p *containerPool) acquire(ctx context.Context, image string, rt *container_runtime.ContainerRuntimeContext) (string, bool, error) {
+	p.mu.Lock()
+	if idle := p.idle[image]; len(idle) > 0 {
+		name := idle[len(idle)-1]
+		p.idle[image] = idle[:len(idle)-1]
+		p.mu.Unlock()
+		return name, true, nil
+	}
+	p.mu.Unlock()
+
+	name, err := rt.CreateContainer(ctx, image)
+	if err != nil {
+		return "", false, err
+	}
+	if err := rt.StartContainer(ctx, name); err != nil {
+		return "", false, err
+	}
+	return name, true, nil
+}
+
+// release returns a warm container to the pool, up to warmPerImage per
+// image; beyond that it deletes the container itself rather than letting it
+// leak, since acquire now hands back poolable containers for every run and
+// there is nowhere else that would otherwise reap the overflow.
+func (p *containerPool) release(ctx context.Context, image, container string, rt *container_runtime.ContainerRuntimeContext) {
+	p.mu.Lock()
+	if len(p.idle[image]) < p.warmPerImage {
+		p.idle[image] = append(p.idle[image], container)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	rt.DeleteContainer(ctx, container)
+}
@@ -0,0 +1,96 @@
+package imagerunner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Eventual-Inc/Daft/pkg/container_runtime"
+)
+
+func TestContainerPoolReleaseThenAcquireReuses(t *testing.T) {
+	p := newContainerPool(2)
+
+	rt := container_runtime.NewContainerRuntimeContext("/does/not/exist.sock", t.TempDir())
+	p.release(context.Background(), "img", "c1", rt)
+
+	name, warm, err := p.acquire(context.Background(), "img", rt)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if !warm {
+		t.Fatal("acquire should report warm=true for a container handed back by release")
+	}
+	if name != "c1" {
+		t.Fatalf("acquire returned %q, want the released container %q", name, "c1")
+	}
+}
+
+func TestContainerPoolReleaseBeyondWarmPerImageDoesNotPoolOverflow(t *testing.T) {
+	p := newContainerPool(1)
+	rt := container_runtime.NewContainerRuntimeContext("/does/not/exist.sock", t.TempDir())
+
+	p.release(context.Background(), "img", "c1", rt)
+	p.release(context.Background(), "img", "c2", rt) // over the cap; should not be added to idle
+
+	p.mu.Lock()
+	idle := append([]string(nil), p.idle["img"]...)
+	p.mu.Unlock()
+
+	if len(idle) != 1 {
+		t.Fatalf("idle pool for img has %d entries, want 1 (warmPerImage cap)", len(idle))
+	}
+	if idle[0] != "c1" {
+		t.Fatalf("idle pool kept %q, want the first-released %q", idle[0], "c1")
+	}
+}
+
+func TestContainerPoolAcquireIsolatesByImage(t *testing.T) {
+	p := newContainerPool(2)
+	rt := container_runtime.NewContainerRuntimeContext("/does/not/exist.sock", t.TempDir())
+
+	p.release(context.Background(), "image-a", "a1", rt)
+
+	p.mu.Lock()
+	idleB := len(p.idle["image-b"])
+	p.mu.Unlock()
+	if idleB != 0 {
+		t.Fatal("releasing a container for image-a must not make it available for image-b")
+	}
+}
+
+func TestImageLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	lru := newImageLRU(2, func(ctx context.Context, image string) {
+		evicted = append(evicted, image)
+	})
+
+	pulls := map[string]int{}
+	pull := func(ctx context.Context, image string) { pulls[image]++ }
+
+	if err := lru.touch(context.Background(), "a", pull); err != nil {
+		t.Fatalf("touch a: %v", err)
+	}
+	if err := lru.touch(context.Background(), "b", pull); err != nil {
+		t.Fatalf("touch b: %v", err)
+	}
+	// Touching "a" again marks it most-recently-used, so "b" becomes the LRU
+	// entry once a third distinct image pushes the cache over its max.
+	if err := lru.touch(context.Background(), "a", pull); err != nil {
+		t.Fatalf("touch a again: %v", err)
+	}
+	if err := lru.touch(context.Background(), "c", pull); err != nil {
+		t.Fatalf("touch c: %v", err)
+	}
+
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted = %v, want [\"b\"]", evicted)
+	}
+	if pulls["a"] != 1 {
+		t.Fatalf("pull called %d times for already-resident image a, want 1", pulls["a"])
+	}
+
+	stats := lru.Stats()
+	if stats.Images != 2 || stats.MaxImages != 2 || stats.Evictions != 1 {
+		t.Fatalf("Stats() = %+v, want Images=2 MaxImages=2 Evictions=1", stats)
+	}
+}
@@ -0,0 +1,44 @@
+package imagerunner
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeZip streams the contents of dir into w as a zip archive, for the
+// GET /runs/{id}/artifacts handler.
+func writeZip(dir string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		entry, err := zw.Create(rel)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(entry, f)
+		return err
+	})
+}
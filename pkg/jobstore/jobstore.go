@@ -0,0 +1,185 @@
+// Package jobstore persists Job resources in an embedded BoltDB file so
+// daftlet's control plane survives a restart without losing track of
+// in-flight work, and so idempotency keys can be deduped across requests.
+package jobstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Status is the lifecycle state of a Job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Job is the persisted record backing the /v1/jobs API.
+type Job struct {
+	ID             string            `json:"id"`
+	Image          string            `json:"image"`
+	DatasetURI     string            `json:"dataset_uri"`
+	Args           []string          `json:"args,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Resources      Resources         `json:"resources,omitempty"`
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
+
+	Status    Status    `json:"status"`
+	ExitCode  int       `json:"exit_code,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Resources is a coarse request for container resources. It is stored with
+// the job and returned by the API; daftlet does not apply it to the
+// container as limits.
+type Resources struct {
+	CPUMillis int64 `json:"cpu_millis,omitempty"`
+	MemoryMiB int64 `json:"memory_mib,omitempty"`
+}
+
+var (
+	jobsBucket        = []byte("jobs")
+	idempotencyBucket = []byte("idempotency")
+)
+
+// Store is a BoltDB-backed Job repository. It is safe for concurrent use.
+type Store struct {
+	db *bbolt.DB
+}
+
+// Open opens (creating if necessary) the BoltDB file at path and ensures its
+// buckets exist.
+func Open(path string) (*Store, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("jobstore: open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(jobsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(idempotencyBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("jobstore: init buckets: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close releases the underlying BoltDB file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Create persists a new job and, if job.IdempotencyKey is set, indexes it so
+// FindByIdempotencyKey can return the same job ID for a retried request.
+//
+// The idempotency check-and-set happens inside the same BoltDB transaction
+// as the insert, so two concurrent Creates with the same key can't both
+// observe no existing job: the loser sees the winner's job already indexed
+// and returns it instead of erroring, with created=false. Callers should
+// use the returned job (not their input job) and only kick off work for it
+// when created is true.
+func (s *Store) Create(job Job) (result Job, created bool, err error) {
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		if job.IdempotencyKey != "" {
+			idemp := tx.Bucket(idempotencyBucket)
+			if existing := idemp.Get([]byte(job.IdempotencyKey)); existing != nil {
+				v := tx.Bucket(jobsBucket).Get(existing)
+				if v == nil {
+					return fmt.Errorf("jobstore: idempotency key %q references unknown job %s", job.IdempotencyKey, existing)
+				}
+				return json.Unmarshal(v, &result)
+			}
+			if err := idemp.Put([]byte(job.IdempotencyKey), []byte(job.ID)); err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(jobsBucket).Put([]byte(job.ID), data); err != nil {
+			return err
+		}
+		result = job
+		created = true
+		return nil
+	})
+	return result, created, err
+}
+
+// FindByIdempotencyKey returns the job ID previously created with key, or
+// ok=false if none exists.
+func (s *Store) FindByIdempotencyKey(key string) (id string, ok bool, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(idempotencyBucket).Get([]byte(key))
+		if v != nil {
+			id, ok = string(v), true
+		}
+		return nil
+	})
+	return id, ok, err
+}
+
+// Get returns the job with the given ID.
+func (s *Store) Get(id string) (Job, error) {
+	var job Job
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(jobsBucket).Get([]byte(id))
+		if v == nil {
+			return fmt.Errorf("jobstore: unknown job %q", id)
+		}
+		return json.Unmarshal(v, &job)
+	})
+	return job, err
+}
+
+// Update loads the job with the given ID, applies mutate, and persists the
+// result.
+func (s *Store) Update(id string, mutate func(*Job)) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(jobsBucket)
+		v := bucket.Get([]byte(id))
+		if v == nil {
+			return fmt.Errorf("jobstore: unknown job %q", id)
+		}
+
+		var job Job
+		if err := json.Unmarshal(v, &job); err != nil {
+			return err
+		}
+		mutate(&job)
+		job.UpdatedAt = time.Now()
+
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), data)
+	})
+}
+
+// Delete removes the job record. It does not cancel an in-flight run;
+// callers should cancel via the runner first.
+func (s *Store) Delete(id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(id))
+	})
+}
@@ -0,0 +1,116 @@
+package jobstore
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestCreateDedupesByIdempotencyKey(t *testing.T) {
+	s := openTestStore(t)
+
+	job := Job{ID: uuid.NewString(), Image: "reader:latest", IdempotencyKey: "retry-1", Status: StatusPending}
+	first, created, err := s.Create(job)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !created {
+		t.Fatal("first Create with a fresh idempotency key should report created=true")
+	}
+
+	retry := job
+	retry.ID = uuid.NewString() // simulates a client retry generating a new request, same key
+	second, created, err := s.Create(retry)
+	if err != nil {
+		t.Fatalf("Create (retry): %v", err)
+	}
+	if created {
+		t.Fatal("Create with a colliding idempotency key should report created=false")
+	}
+	if second.ID != first.ID {
+		t.Fatalf("retried Create returned job %s, want original job %s", second.ID, first.ID)
+	}
+}
+
+func TestCreateConcurrentSameIdempotencyKeyDedupes(t *testing.T) {
+	s := openTestStore(t)
+
+	const n = 20
+	ids := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job, _, err := s.Create(Job{ID: uuid.NewString(), Image: "reader:latest", IdempotencyKey: "race-key", Status: StatusPending})
+			if err != nil {
+				t.Errorf("Create: %v", err)
+				return
+			}
+			ids[i] = job.ID
+		}(i)
+	}
+	wg.Wait()
+
+	for i, id := range ids[1:] {
+		if id != ids[0] {
+			t.Fatalf("goroutine %d resolved to job %s, want %s (every racer should land on the same job)", i+1, id, ids[0])
+		}
+	}
+}
+
+func TestUpdateUnknownJob(t *testing.T) {
+	s := openTestStore(t)
+	err := s.Update("does-not-exist", func(j *Job) { j.Status = StatusCancelled })
+	if err == nil {
+		t.Fatal("Update of an unknown job id should return an error")
+	}
+}
+
+func TestUpdateDoesNotClobberAlreadyTerminalStatusWhenCallerChecks(t *testing.T) {
+	s := openTestStore(t)
+	job, _, err := s.Create(Job{ID: uuid.NewString(), Status: StatusPending})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Simulate a cancel racing a run's own status update: both read-modify-write
+	// inside Update, so whichever runs second sees the other's write.
+	if err := s.Update(job.ID, func(j *Job) { j.Status = StatusCancelled }); err != nil {
+		t.Fatalf("Update (cancel): %v", err)
+	}
+
+	var sawCancelled bool
+	if err := s.Update(job.ID, func(j *Job) {
+		if j.Status == StatusCancelled {
+			sawCancelled = true
+			return
+		}
+		j.Status = StatusRunning
+	}); err != nil {
+		t.Fatalf("Update (run): %v", err)
+	}
+	if !sawCancelled {
+		t.Fatal("a later Update should observe the status set by an earlier one, not a stale read")
+	}
+
+	got, err := s.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != StatusCancelled {
+		t.Fatalf("job status = %v, want %v", got.Status, StatusCancelled)
+	}
+}
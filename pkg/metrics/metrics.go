@@ -0,0 +1,63 @@
+// Package metrics defines the Prometheus instrumentation for daftlet's job
+// control plane: image pulls, container starts, run durations, and failure
+// classes.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics implements imagerunner.MetricsRecorder against Prometheus
+// collectors registered on New.
+type Metrics struct {
+	pulls    *prometheus.CounterVec
+	starts   *prometheus.CounterVec
+	runSecs  *prometheus.HistogramVec
+	failures *prometheus.CounterVec
+}
+
+// New registers daftlet's collectors on reg and returns a Metrics that
+// records against them. Pass prometheus.DefaultRegisterer to expose them on
+// the default /metrics handler.
+func New(reg prometheus.Registerer) *Metrics {
+	factory := promauto.With(reg)
+
+	return &Metrics{
+		pulls: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "daft_image_pulls_total",
+			Help: "Count of reader image pulls attempted, by image.",
+		}, []string{"image"}),
+		starts: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "daft_container_starts_total",
+			Help: "Count of runs that obtained a started reader container, fresh or reused from the warm pool, by image.",
+		}, []string{"image"}),
+		runSecs: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "daft_run_duration_seconds",
+			Help:    "Wall-clock duration of a reader container run, by image.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"image"}),
+		failures: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "daft_run_failures_total",
+			Help: "Count of run failures, by failure class (pull, start, run, cancelled).",
+		}, []string{"class"}),
+	}
+}
+
+func (m *Metrics) RecordPull(image string) {
+	m.pulls.WithLabelValues(image).Inc()
+}
+
+func (m *Metrics) RecordStart(image string) {
+	m.starts.WithLabelValues(image).Inc()
+}
+
+func (m *Metrics) RecordRunDuration(image string, d time.Duration) {
+	m.runSecs.WithLabelValues(image).Observe(d.Seconds())
+}
+
+func (m *Metrics) RecordFailure(class string) {
+	m.failures.WithLabelValues(class).Inc()
+}
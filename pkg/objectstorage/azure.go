@@ -0,0 +1,60 @@
+package objectstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+// azureBlobObjectStore downloads blobs from Azure Blob Storage.
+type azureBlobObjectStore struct {
+	client *azblob.Client
+}
+
+func newAzureBlobObjectStore(ctx context.Context, cfg Config) (ObjectStore, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("objectstorage: azure backend requires an account endpoint")
+	}
+
+	cred, err := azblob.NewSharedKeyCredential(cfg.AccessKey, cfg.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	client, err := azblob.NewClientWithSharedKeyCredential(cfg.Endpoint, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &azureBlobObjectStore{client: client}, nil
+}
+
+func (o *azureBlobObjectStore) StatObject(ctx context.Context, path string) (ObjectInfo, error) {
+	container, blob := splitBucketKey(path)
+
+	props, err := o.client.ServiceClient().NewContainerClient(container).NewBlobClient(blob).GetProperties(ctx, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	info := ObjectInfo{}
+	if props.ETag != nil {
+		info.ETag = string(*props.ETag)
+	}
+	if props.ContentLength != nil {
+		info.Size = *props.ContentLength
+	}
+	return info, nil
+}
+
+func (o *azureBlobObjectStore) DownloadObject(ctx context.Context, path string, w io.Writer) (int64, error) {
+	container, blob := splitBucketKey(path)
+
+	resp, err := o.client.DownloadStream(ctx, container, blob, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return io.Copy(w, resp.Body)
+}
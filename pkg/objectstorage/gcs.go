@@ -0,0 +1,44 @@
+package objectstorage
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+// gcsObjectStore downloads objects from Google Cloud Storage using
+// application default credentials.
+type gcsObjectStore struct {
+	client *storage.Client
+}
+
+func newGCSObjectStore(ctx context.Context, cfg Config) (ObjectStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsObjectStore{client: client}, nil
+}
+
+func (o *gcsObjectStore) StatObject(ctx context.Context, path string) (ObjectInfo, error) {
+	bucket, object := splitBucketKey(path)
+
+	attrs, err := o.client.Bucket(bucket).Object(object).Attrs(ctx)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{ETag: attrs.Etag, Size: attrs.Size}, nil
+}
+
+func (o *gcsObjectStore) DownloadObject(ctx context.Context, path string, w io.Writer) (int64, error) {
+	bucket, object := splitBucketKey(path)
+
+	r, err := o.client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	return io.Copy(w, r)
+}
@@ -0,0 +1,57 @@
+package objectstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Eventual-Inc/Daft/pkg/cache"
+)
+
+// httpObjectStore downloads an object with a single plain HTTP(S) GET, for
+// a presigned URL or CDN-fronted dataset mirror that isn't behind a cloud
+// SDK. Unlike the S3/GCS/Azure backends it has no bucket/key structure:
+// path is the full URL to fetch. It's the one caller of
+// cache.NewGzipAwareHTTPClient, since a single non-ranged fetch is exactly
+// the case that client is safe for; see the comment in
+// newS3CompatibleObjectStore for why the S3-compatible backend can't use it.
+type httpObjectStore struct {
+	client *http.Client
+}
+
+func newHTTPObjectStore() ObjectStore {
+	return &httpObjectStore{client: cache.NewGzipAwareHTTPClient(nil)}
+}
+
+func (o *httpObjectStore) StatObject(ctx context.Context, path string) (ObjectInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, path, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ObjectInfo{}, fmt.Errorf("objectstorage: HEAD %s: %s", path, resp.Status)
+	}
+	return ObjectInfo{ETag: resp.Header.Get("ETag"), Size: resp.ContentLength}, nil
+}
+
+func (o *httpObjectStore) DownloadObject(ctx context.Context, path string, w io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("objectstorage: GET %s: %s", path, resp.Status)
+	}
+	return io.Copy(w, resp.Body)
+}
@@ -0,0 +1,10 @@
+package objectstorage
+
+import "strings"
+
+// splitBucketKey splits a "bucket/key/with/slashes" path (as produced by New
+// after stripping the URI scheme) into its bucket and key components.
+func splitBucketKey(path string) (bucket, key string) {
+	bucket, key, _ = strings.Cut(path, "/")
+	return bucket, key
+}
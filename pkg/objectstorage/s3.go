@@ -0,0 +1,102 @@
+package objectstorage
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// awsS3ObjectStore downloads objects from AWS S3 using the default AWS
+// credential chain (env vars, shared config, IAM role).
+type awsS3ObjectStore struct {
+	client *s3.Client
+}
+
+// NewAwsS3ObjectStore constructs an ObjectStore backed by AWS S3 in the
+// given region. Kept exported for callers that already have a region picked
+// out rather than a full URI to route through New.
+func NewAwsS3ObjectStore(ctx context.Context, cfg aws.Config) ObjectStore {
+	return &awsS3ObjectStore{client: s3.NewFromConfig(cfg)}
+}
+
+func newAwsS3ObjectStore(ctx context.Context, cfg Config) (ObjectStore, error) {
+	region := cfg.Region
+	if region == "" {
+		region = "us-west-2"
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	return NewAwsS3ObjectStore(ctx, awsCfg), nil
+}
+
+// newS3CompatibleObjectStore builds an S3 client pointed at a non-AWS
+// endpoint (MinIO, Ceph RGW, localstack, …), using static credentials and
+// path-style addressing when requested.
+func newS3CompatibleObjectStore(ctx context.Context, cfg Config) (ObjectStore, error) {
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	// Deliberately not using cache.NewGzipAwareHTTPClient here: the
+	// manager.Downloader below issues ranged, concurrent multipart GETs and
+	// assembles the object from their Content-Range offsets, and a
+	// transport that transparently gunzips each part's body out from under
+	// it would corrupt that assembly. The gzip-aware client is only safe
+	// for a single, non-ranged plain-HTTP fetch.
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx,
+		awsconfig.WithRegion(region),
+		awsconfig.WithCredentialsProvider(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     cfg.AccessKey,
+				SecretAccessKey: cfg.SecretKey,
+			}, nil
+		})),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = cfg.PathStyle
+	})
+
+	return &awsS3ObjectStore{client: client}, nil
+}
+
+func (o *awsS3ObjectStore) StatObject(ctx context.Context, path string) (ObjectInfo, error) {
+	bucket, key := splitBucketKey(path)
+
+	out, err := o.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	info := ObjectInfo{Size: aws.ToInt64(out.ContentLength)}
+	if out.ETag != nil {
+		info.ETag = strings.Trim(*out.ETag, `"`)
+	}
+	return info, nil
+}
+
+func (o *awsS3ObjectStore) DownloadObject(ctx context.Context, path string, w io.Writer) (int64, error) {
+	bucket, key := splitBucketKey(path)
+
+	downloader := downloaderFor(o.client, w)
+	return downloader.Download(ctx, asWriterAt(w), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+}
@@ -0,0 +1,46 @@
+package objectstorage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+// sequentialWriterAt adapts a plain io.Writer to io.WriterAt for callers
+// that only have a stream (e.g. an HTTP response body) rather than a
+// seekable file. It only supports writes that arrive in offset order, which
+// holds as long as the downloader's concurrency is capped to 1.
+type sequentialWriterAt struct {
+	w      io.Writer
+	offset int64
+}
+
+func (s *sequentialWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off != s.offset {
+		return 0, fmt.Errorf("objectstorage: out-of-order write at offset %d, expected %d (downloader concurrency must be 1)", off, s.offset)
+	}
+	n, err := s.w.Write(p)
+	s.offset += int64(n)
+	return n, err
+}
+
+// asWriterAt returns w as-is if it already supports random access, and
+// otherwise wraps it in a sequentialWriterAt.
+func asWriterAt(w io.Writer) io.WriterAt {
+	if wa, ok := w.(io.WriterAt); ok {
+		return wa
+	}
+	return &sequentialWriterAt{w: w}
+}
+
+// downloaderFor returns a manager.Downloader forced to sequential
+// concurrency when w doesn't support random access.
+func downloaderFor(client manager.DownloadAPIClient, w io.Writer) *manager.Downloader {
+	if _, ok := w.(io.WriterAt); ok {
+		return manager.NewDownloader(client)
+	}
+	return manager.NewDownloader(client, func(d *manager.Downloader) {
+		d.Concurrency = 1
+	})
+}